@@ -0,0 +1,554 @@
+//go:build linux
+
+package gopisysfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The constants and structs below mirror the GPIO v2 character-device ABI defined in
+// <linux/gpio.h>. They let cdevPort talk to /dev/gpiochipN directly instead of going through
+// the deprecated /sys/class/gpio tree.
+const (
+	gpioMaxLines = 64
+
+	gpioV2LineFlagInput        = 1 << 2
+	gpioV2LineFlagOutput       = 1 << 3
+	gpioV2LineFlagActiveLow    = 1 << 1
+	gpioV2LineFlagEdgeRising   = 1 << 4
+	gpioV2LineFlagEdgeFalling  = 1 << 5
+	gpioV2LineFlagOpenDrain    = 1 << 6
+	gpioV2LineFlagOpenSource   = 1 << 7
+	gpioV2LineFlagBiasPullUp   = 1 << 8
+	gpioV2LineFlagBiasPullDown = 1 << 9
+	gpioV2LineFlagBiasDisabled = 1 << 10
+)
+
+// gpioIoctlMagic is the ioctl type byte the kernel assigns the GPIO char-device ABI
+// (<linux/gpio.h>'s 0xB4) - it is not the ASCII code for the letter 'B'.
+const gpioIoctlMagic = 0xb4
+
+var (
+	gpioGetLineIoctl       = iowr(gpioIoctlMagic, 0x07, unsafe.Sizeof(gpioV2LineRequest{}))
+	gpioLineSetConfIoctl   = iowr(gpioIoctlMagic, 0x0D, unsafe.Sizeof(gpioV2LineConfig{}))
+	gpioLineGetValuesIoctl = iowr(gpioIoctlMagic, 0x0E, unsafe.Sizeof(gpioV2LineValues{}))
+	gpioLineSetValuesIoctl = iowr(gpioIoctlMagic, 0x0F, unsafe.Sizeof(gpioV2LineValues{}))
+)
+
+// iowr reproduces the _IOWR macro from <asm-generic/ioctl.h> for a fixed-size payload.
+func iowr(t byte, nr byte, size uintptr) uintptr {
+	const iocRead = 2
+	const iocWrite = 1
+	return (uintptr(iocRead|iocWrite) << 30) | (uintptr(t) << 8) | uintptr(nr) | (size << 16)
+}
+
+type gpioV2LineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+type gpioV2LineConfigAttribute struct {
+	Flags    uint64
+	Values   uint64
+	Debounce uint32
+	_        [4]byte
+}
+
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [10]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	Offsets      [gpioMaxLines]uint32
+	Consumer     [32]byte
+	Config       gpioV2LineConfig
+	NumLines     uint32
+	EventBufSize uint32
+	Padding      [5]uint32
+	Fd           int32
+}
+
+type gpioV2LineEvent struct {
+	Timestamp uint64
+	ID        uint32
+	Offset    uint32
+	Seqno     uint32
+	LineSeqno uint32
+	Padding   [6]uint32
+}
+
+const (
+	gpioV2LineEventRisingEdge  = 1
+	gpioV2LineEventFallingEdge = 2
+)
+
+func openChip(chipPath string) (int, error) {
+	f, err := os.OpenFile(chipPath, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	return int(f.Fd()), nil
+}
+
+// cdevPort is the GPIOPort implementation backed by the Linux GPIO character device ABI.
+type cdevPort struct {
+	mu        sync.Mutex
+	host      *pi
+	offset    uint32
+	lineFd    int
+	mode      GPIOMode
+	activeLow bool
+	resetters []func()
+
+	forceSoftwarePWM bool
+	softPWM          *softwarePWM
+	pwmChannel       *int
+	pwmResetterIdx   int
+}
+
+func newCdevPort(host *pi, port int) *cdevPort {
+	return &cdevPort{
+		host:           host,
+		offset:         uint32(port),
+		lineFd:         -1,
+		mode:           GPIOInput,
+		resetters:      make([]func(), 0),
+		pwmResetterIdx: -1,
+	}
+}
+
+func (p *cdevPort) String() string {
+	return fmt.Sprintf("%v line %v", p.host, p.offset)
+}
+
+func (p *cdevPort) IsEnabled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lineFd >= 0
+}
+
+func (p *cdevPort) Enable() error {
+	return p.EnableContext(context.Background())
+}
+
+// EnableContext is the context-aware form of Enable. The underlying ioctl is a single fast
+// syscall rather than a poll loop, so ctx is only checked before issuing it; a ctx that is
+// already cancelled short-circuits without touching the chip.
+func (p *cdevPort) EnableContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd >= 0 {
+		return nil
+	}
+	return p.request(modeFlags(p.mode, p.activeLow))
+}
+
+func (p *cdevPort) Reset() error {
+	return p.ResetContext(context.Background())
+}
+
+// ResetContext is the context-aware form of Reset; see EnableContext for why ctx is only
+// checked up front.
+func (p *cdevPort) ResetContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return nil
+	}
+	for _, r := range p.resetters {
+		r()
+	}
+	p.resetters = nil
+	err := unix.Close(p.lineFd)
+	p.lineFd = -1
+	return err
+}
+
+func (p *cdevPort) SetMode(mode GPIOMode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if mode == GPIOPWM {
+		return fmt.Errorf("GPIOMode %v must be started with SetPWM, not SetMode", mode)
+	}
+
+	p.mode = mode
+	if p.lineFd < 0 {
+		return nil
+	}
+	return p.reconfigure(modeFlags(mode, p.activeLow))
+}
+
+func (p *cdevPort) IsOutput() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mode == GPIOOutput || p.mode == GPIOOutputHigh || p.mode == GPIOOutputLow, nil
+}
+
+func (p *cdevPort) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return fmt.Sprintf("GPIO %v: Reset", p.offset)
+	}
+	val, err := p.getValue()
+	if err != nil {
+		return fmt.Sprintf("GPIO %v: %v", p.offset, err)
+	}
+	return fmt.Sprintf("GPIO %v: mode %v with value %v", p.offset, p.mode, val)
+}
+
+func (p *cdevPort) Value() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return false, fmt.Errorf("GPIO %v is not enabled", p.offset)
+	}
+	return p.getValue()
+}
+
+func (p *cdevPort) SetValue(value bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return fmt.Errorf("GPIO %v is not enabled", p.offset)
+	}
+	return p.setValue(value)
+}
+
+func (p *cdevPort) SetValues(ch <-chan bool) (<-chan error, error) {
+	return p.SetValuesContext(context.Background(), ch)
+}
+
+func (p *cdevPort) SetValuesContext(ctx context.Context, ch <-chan bool) (<-chan error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return nil, fmt.Errorf("GPIO %v is not enabled", p.offset)
+	}
+
+	errch := make(chan error, 1)
+	killer := make(chan bool, 1)
+	p.resetters = append(p.resetters, func() { close(killer) })
+
+	go func() {
+		defer close(errch)
+		for {
+			select {
+			case <-killer:
+				return
+			case <-ctx.Done():
+				errch <- ctx.Err()
+				return
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+				if err := p.SetValue(v); err != nil {
+					errch <- err
+					return
+				}
+			}
+		}
+	}()
+
+	return errch, nil
+}
+
+func (p *cdevPort) Values(buffersize int) (<-chan Event, error) {
+	return p.ValuesContext(context.Background(), buffersize)
+}
+
+func (p *cdevPort) ValuesContext(ctx context.Context, buffersize int) (<-chan Event, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineFd < 0 {
+		return nil, fmt.Errorf("GPIO %v is not enabled", p.offset)
+	}
+
+	flags := modeFlags(p.mode, p.activeLow) | gpioV2LineFlagEdgeRising | gpioV2LineFlagEdgeFalling
+	if err := p.reconfigure(flags); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event, buffersize)
+	done := make(chan bool)
+	p.resetters = append(p.resetters, func() { close(done) })
+
+	go func() {
+		buf := make([]byte, unsafe.Sizeof(gpioV2LineEvent{}))
+		pollFds := []unix.PollFd{{Fd: int32(p.lineFd), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+			// Poll with a short timeout rather than blocking in Read directly, so done/ctx
+			// are re-checked promptly instead of only after the next real edge arrives.
+			pollFds[0].Revents = 0
+			n, err := unix.Poll(pollFds, int(pollInterval/time.Millisecond))
+			if err != nil || n == 0 || pollFds[0].Revents&unix.POLLIN == 0 {
+				continue
+			}
+			n, err = unix.Read(p.lineFd, buf)
+			if err != nil || n != len(buf) {
+				continue
+			}
+			evt := (*gpioV2LineEvent)(unsafe.Pointer(&buf[0]))
+			value := evt.ID == gpioV2LineEventRisingEdge
+			select {
+			case ch <- Event{Value: value, Timestamp: time.Unix(0, int64(evt.Timestamp))}:
+			case <-done:
+				return
+			default:
+				info("GPIO cdev monitor on %v dropped an event; channel full\n", p)
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// ForceSoftwarePWM overrides the automatic hardware/software PWM selection made by SetPWM.
+func (p *cdevPort) ForceSoftwarePWM(force bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forceSoftwarePWM = force
+}
+
+// SetPWM starts driving a PWM waveform of the given frequency (Hz) and duty cycle (0-1) on
+// this line, using the hardware pwmchip0 channel when the line is GPIO12/13/18/19 (unless
+// overridden by ForceSoftwarePWM) and a ticker-driven software fallback otherwise.
+func (p *cdevPort) SetPWM(freqHz float64, duty float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lineFd < 0 {
+		return fmt.Errorf("GPIO %v is not enabled", p.offset)
+	}
+
+	if err := p.stopPWM(); err != nil {
+		return err
+	}
+
+	if channel, ok := hardwarePWMLines[int(p.offset)]; ok && !p.forceSoftwarePWM {
+		if err := p.host.startHardwarePWM(channel, freqHz, duty); err != nil {
+			return err
+		}
+		p.pwmChannel = &channel
+		p.resetters = append(p.resetters, func() { p.host.stopHardwarePWM(channel) })
+		p.pwmResetterIdx = len(p.resetters) - 1
+		return nil
+	}
+
+	sw, err := startSoftwarePWM(p.setValueString, freqHz, duty)
+	if err != nil {
+		return err
+	}
+	p.softPWM = sw
+	p.resetters = append(p.resetters, sw.Stop)
+	p.pwmResetterIdx = len(p.resetters) - 1
+	return nil
+}
+
+// StopPWM stops any PWM waveform started by SetPWM. It is a no-op if SetPWM was never called.
+func (p *cdevPort) StopPWM() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopPWM()
+}
+
+// stopPWM stops any running PWM waveform and clears its resetter; SetPWM also calls it before
+// starting a new waveform, so a second SetPWM without an intervening StopPWM replaces rather
+// than leaks the previous one. Callers must hold p.mu.
+func (p *cdevPort) stopPWM() error {
+	p.clearPWMResetter()
+
+	if p.softPWM != nil {
+		p.softPWM.Stop()
+		p.softPWM = nil
+	}
+	if p.pwmChannel != nil {
+		err := p.host.stopHardwarePWM(*p.pwmChannel)
+		p.pwmChannel = nil
+		return err
+	}
+	return nil
+}
+
+// clearPWMResetter drops the resetter SetPWM registered for the current PWM waveform, so that
+// a later Reset doesn't call sw.Stop/stopHardwarePWM a second time.
+func (p *cdevPort) clearPWMResetter() {
+	if p.pwmResetterIdx < 0 {
+		return
+	}
+	p.resetters = append(p.resetters[:p.pwmResetterIdx], p.resetters[p.pwmResetterIdx+1:]...)
+	p.pwmResetterIdx = -1
+}
+
+// setValueString adapts setValue to the string-based signature softwarePWM drives its line
+// through, matching the sysfs backend's "0"/"1" convention.
+func (p *cdevPort) setValueString(value string) error {
+	return p.setValue(value == high)
+}
+
+// ValuesFiltered is like Values, but debounces and rate-limits the raw edge events per opts
+// before emitting them.
+func (p *cdevPort) ValuesFiltered(buffersize int, opts FilterOptions) (<-chan Event, error) {
+	raw, err := p.Values(buffersize)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan bool)
+	p.mu.Lock()
+	p.resetters = append(p.resetters, func() { close(done) })
+	p.mu.Unlock()
+
+	return filterEvents(raw, buffersize, opts, done), nil
+}
+
+func (p *cdevPort) request(flags uint64) error {
+	var req gpioV2LineRequest
+	req.Offsets[0] = p.offset
+	req.NumLines = 1
+	copy(req.Consumer[:], "gopi-sysfs")
+	req.Config.Flags = flags
+	req.EventBufSize = 4
+
+	if err := ioctl(p.host.chipFd, gpioGetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return fmt.Errorf("requesting GPIO line %v: %w", p.offset, err)
+	}
+	p.lineFd = int(req.Fd)
+	return nil
+}
+
+func (p *cdevPort) reconfigure(flags uint64) error {
+	var cfg gpioV2LineConfig
+	cfg.Flags = flags
+	return ioctl(p.lineFd, gpioLineSetConfIoctl, unsafe.Pointer(&cfg))
+}
+
+func (p *cdevPort) getValue() (bool, error) {
+	var vals gpioV2LineValues
+	vals.Mask = 1
+	if err := ioctl(p.lineFd, gpioLineGetValuesIoctl, unsafe.Pointer(&vals)); err != nil {
+		return false, err
+	}
+	return vals.Bits&1 != 0, nil
+}
+
+func (p *cdevPort) setValue(value bool) error {
+	var vals gpioV2LineValues
+	vals.Mask = 1
+	if value {
+		vals.Bits = 1
+	}
+	return ioctl(p.lineFd, gpioLineSetValuesIoctl, unsafe.Pointer(&vals))
+}
+
+// modeFlags translates a GPIOMode (plus whether the line is active-low) into the GPIO v2
+// request/config flags that express it.
+func modeFlags(mode GPIOMode, activeLow bool) uint64 {
+	var flags uint64
+	switch mode {
+	case GPIOInput:
+		flags = gpioV2LineFlagInput
+	case GPIOInputPullUp:
+		flags = gpioV2LineFlagInput | gpioV2LineFlagBiasPullUp
+	case GPIOInputPullDown:
+		flags = gpioV2LineFlagInput | gpioV2LineFlagBiasPullDown
+	case GPIOOutput, GPIOOutputLow, GPIOOutputHigh:
+		flags = gpioV2LineFlagOutput
+	}
+	if activeLow {
+		flags |= gpioV2LineFlagActiveLow
+	}
+	return flags
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func init() {
+	groupSetBitsCdev = cdevGroupSetBits
+	groupReadBitsCdev = cdevGroupReadBits
+	groupCloseCdev = cdevGroupClose
+	newCdevPortFn = func(host *pi, port int) GPIOPort { return newCdevPort(host, port) }
+	openChipFn = openChip
+}
+
+// cdevGroupSetBits and cdevGroupReadBits give PortGroup an atomic fast path: since all of the
+// group's lines are requested together in a single GPIO_V2_GET_LINE_IOCTL call, a later
+// GPIO_V2_LINE_SET_VALUES_IOCTL/GPIO_V2_LINE_GET_VALUES_IOCTL on that one request fd applies to
+// every line in the same kernel operation instead of N independent per-line syscalls.
+func cdevGroupSetBits(g *PortGroup, mask, values uint64) error {
+	if err := ensureGroupRequest(g, gpioV2LineFlagOutput); err != nil {
+		return err
+	}
+	var vals gpioV2LineValues
+	vals.Mask = mask
+	vals.Bits = values
+	return ioctl(g.groupFd, gpioLineSetValuesIoctl, unsafe.Pointer(&vals))
+}
+
+func cdevGroupReadBits(g *PortGroup) (uint64, error) {
+	if err := ensureGroupRequest(g, gpioV2LineFlagInput); err != nil {
+		return 0, err
+	}
+	var vals gpioV2LineValues
+	vals.Mask = ^uint64(0)
+	if err := ioctl(g.groupFd, gpioLineGetValuesIoctl, unsafe.Pointer(&vals)); err != nil {
+		return 0, err
+	}
+	return vals.Bits, nil
+}
+
+// cdevGroupClose closes the group's shared line-request fd and resets it to -1, so a later
+// SetBits/ReadBits goes through ensureGroupRequest to re-request the (possibly reconfigured)
+// lines instead of reusing a stale fd.
+func cdevGroupClose(g *PortGroup) error {
+	err := unix.Close(g.groupFd)
+	g.groupFd = -1
+	return err
+}
+
+func ensureGroupRequest(g *PortGroup, flags uint64) error {
+	if g.groupFd >= 0 {
+		return nil
+	}
+	var req gpioV2LineRequest
+	copy(req.Offsets[:], g.offsets)
+	req.NumLines = uint32(len(g.offsets))
+	copy(req.Consumer[:], "gopi-sysfs-group")
+	req.Config.Flags = flags
+	if err := ioctl(g.host.chipFd, gpioGetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return fmt.Errorf("requesting GPIO line group: %w", err)
+	}
+	g.groupFd = int(req.Fd)
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package gopisysfs
+
+import "testing"
+
+// TestGPIOV2IoctlNumbers pins the computed ioctl numbers against the GPIO v2 char-device ABI
+// in <linux/gpio.h>, so a future tweak to iowr or its arguments can't silently reintroduce the
+// wrong magic byte (0xb4, not the ASCII code for 'B') or the wrong command number.
+func TestGPIOV2IoctlNumbers(t *testing.T) {
+	const iocRead, iocWrite = 2, 1
+
+	cases := []struct {
+		name  string
+		ioctl uintptr
+		nr    byte
+	}{
+		{"GPIO_V2_GET_LINE_IOCTL", gpioGetLineIoctl, 0x07},
+		{"GPIO_V2_LINE_SET_CONFIG_IOCTL", gpioLineSetConfIoctl, 0x0D},
+		{"GPIO_V2_LINE_GET_VALUES_IOCTL", gpioLineGetValuesIoctl, 0x0E},
+		{"GPIO_V2_LINE_SET_VALUES_IOCTL", gpioLineSetValuesIoctl, 0x0F},
+	}
+
+	for _, c := range cases {
+		if dir := byte(c.ioctl >> 30); dir != iocRead|iocWrite {
+			t.Errorf("%v: expected IOWR direction bits, got 0x%x", c.name, dir)
+		}
+		if magic := byte(c.ioctl >> 8); magic != 0xb4 {
+			t.Errorf("%v: expected magic byte 0x%x, got 0x%x", c.name, 0xb4, magic)
+		}
+		if nr := byte(c.ioctl); nr != c.nr {
+			t.Errorf("%v: expected command number 0x%x, got 0x%x", c.name, c.nr, nr)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gopisysfs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestValuesContextCancel(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	port := host.GPIO(4)
+
+	if err := port.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.SetMode(GPIOInput); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := port.ValuesContext(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected no event after cancellation")
+		}
+	case <-time.After(200 * time.Millisecond):
+		// the monitor goroutine stopped but never closes ch itself; not receiving anything
+		// further is success here.
+	}
+}
+
+func TestSetValuesContextCancel(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	port := host.GPIO(4)
+
+	if err := port.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.SetMode(GPIOOutput); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	values := make(chan bool)
+	errch, err := port.SetValuesContext(ctx, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	select {
+	case err, ok := <-errch:
+		if !ok {
+			t.Fatal("expected ctx.Err() before the channel closed")
+		}
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected SetValuesContext to stop once ctx was cancelled")
+	}
+}
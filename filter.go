@@ -0,0 +1,91 @@
+package gopisysfs
+
+import "time"
+
+// EdgeFilter restricts which transitions ValuesFiltered emits.
+type EdgeFilter int
+
+const (
+	EdgeBoth EdgeFilter = iota
+	EdgeRising
+	EdgeFalling
+)
+
+// FilterOptions configures ValuesFiltered.
+type FilterOptions struct {
+	// Debounce swallows edges arriving within this long of the last one, emitting the
+	// settled value once the line has been quiet for Debounce. Zero disables debouncing.
+	Debounce time.Duration
+	// MinInterval additionally rate-limits how often two emitted events may follow each
+	// other, independent of Debounce. Zero disables rate-limiting.
+	MinInterval time.Duration
+	// EdgeFilter restricts emitted events to rising, falling, or both transitions.
+	EdgeFilter EdgeFilter
+}
+
+func (opts FilterOptions) matches(e Event) bool {
+	switch opts.EdgeFilter {
+	case EdgeRising:
+		return e.Value
+	case EdgeFalling:
+		return !e.Value
+	default:
+		return true
+	}
+}
+
+// filterEvents wraps raw with debounce, minimum-interval and edge-direction filtering. An
+// edge within opts.Debounce of the previous one is treated as contact bounce and swallowed;
+// once the line has been quiet for opts.Debounce, the settled value is emitted. done stops
+// the filtering goroutine, which otherwise runs for as long as raw is open.
+func filterEvents(raw <-chan Event, buffersize int, opts FilterOptions, done <-chan bool) <-chan Event {
+	out := make(chan Event, buffersize)
+
+	go func() {
+		defer close(out)
+
+		var timerCh <-chan time.Time
+		var pending *Event
+		var lastEmit time.Time
+
+		emit := func(e Event) {
+			if !opts.matches(e) {
+				return
+			}
+			if opts.MinInterval > 0 && !lastEmit.IsZero() && e.Timestamp.Sub(lastEmit) < opts.MinInterval {
+				return
+			}
+			lastEmit = e.Timestamp
+			select {
+			case out <- e:
+			case <-done:
+			}
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case e, ok := <-raw:
+				if !ok {
+					return
+				}
+				if opts.Debounce <= 0 {
+					emit(e)
+					continue
+				}
+				settled := e
+				pending = &settled
+				timerCh = time.After(opts.Debounce)
+			case <-timerCh:
+				if pending != nil {
+					emit(*pending)
+					pending = nil
+				}
+				timerCh = nil
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,54 @@
+package gopisysfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterEventsDebounce(t *testing.T) {
+	raw := make(chan Event, 10)
+	done := make(chan bool)
+	defer close(done)
+
+	out := filterEvents(raw, 10, FilterOptions{Debounce: 20 * time.Millisecond}, done)
+
+	base := time.Now()
+	raw <- Event{Value: true, Timestamp: base}
+	raw <- Event{Value: false, Timestamp: base.Add(1 * time.Millisecond)}
+	raw <- Event{Value: true, Timestamp: base.Add(2 * time.Millisecond)}
+
+	select {
+	case e := <-out:
+		if !e.Value {
+			t.Errorf("expected the settled value true, got %v", e.Value)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected a debounced event to be emitted")
+	}
+
+	select {
+	case e := <-out:
+		t.Fatalf("expected no further events, got %v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFilterEventsEdgeFilter(t *testing.T) {
+	raw := make(chan Event, 10)
+	done := make(chan bool)
+	defer close(done)
+
+	out := filterEvents(raw, 10, FilterOptions{EdgeFilter: EdgeRising}, done)
+
+	raw <- Event{Value: false, Timestamp: time.Now()}
+	raw <- Event{Value: true, Timestamp: time.Now()}
+
+	select {
+	case e := <-out:
+		if !e.Value {
+			t.Errorf("expected only the rising edge to be emitted, got %v", e.Value)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected the rising edge to be emitted")
+	}
+}
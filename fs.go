@@ -0,0 +1,142 @@
+package gopisysfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// checkFile reports whether name exists on the host's filesystem.
+func (p *pi) checkFile(name string) bool {
+	_, err := p.fs.Stat(name)
+	return err == nil
+}
+
+// writeFile writes value to name, truncating any existing content, matching how sysfs GPIO
+// control files expect a single write per operation.
+func (p *pi) writeFile(name, value string) error {
+	f, err := p.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(value)
+	return err
+}
+
+// readFile returns the trimmed contents of name.
+func (p *pi) readFile(name string) (string, error) {
+	data, err := afero.ReadFile(p.fs, name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// awaitFileCreate returns a channel that receives nil once name exists, or an error if it
+// does not appear within timeout or ctx is cancelled first.
+func (p *pi) awaitFileCreate(ctx context.Context, name string, timeout time.Duration) (<-chan error, error) {
+	ch := make(chan error, 1)
+	if p.checkFile(name) {
+		ch <- nil
+		close(ch)
+		return ch, nil
+	}
+	go func() {
+		defer close(ch)
+		limit := time.After(timeout)
+		for {
+			select {
+			case <-ctx.Done():
+				ch <- ctx.Err()
+				return
+			case <-limit:
+				ch <- fmt.Errorf("timed out waiting for %v to be created", name)
+				return
+			case <-time.After(pollInterval):
+				if p.checkFile(name) {
+					ch <- nil
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// awaitFileRemove returns a channel that receives nil once name no longer exists, or an
+// error if it is still present after timeout or ctx is cancelled first.
+func (p *pi) awaitFileRemove(ctx context.Context, name string, timeout time.Duration) (<-chan error, error) {
+	ch := make(chan error, 1)
+	if !p.checkFile(name) {
+		ch <- nil
+		close(ch)
+		return ch, nil
+	}
+	go func() {
+		defer close(ch)
+		limit := time.After(timeout)
+		for {
+			select {
+			case <-ctx.Done():
+				ch <- ctx.Err()
+				return
+			case <-limit:
+				ch <- fmt.Errorf("timed out waiting for %v to be removed", name)
+				return
+			case <-time.After(pollInterval):
+				if !p.checkFile(name) {
+					ch <- nil
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// buildMonitor polls the value file at name and emits an Event on the returned channel
+// whenever its content changes. The returned cleaner stops the polling goroutine; it also
+// stops on its own once ctx is cancelled.
+func (p *pi) buildMonitor(ctx context.Context, name string, buffersize int) (<-chan Event, func(), error) {
+	last, err := p.readFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan Event, buffersize)
+	done := make(chan bool)
+	cleaner := func() {
+		close(done)
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				val, err := p.readFile(name)
+				if err != nil || val == last {
+					continue
+				}
+				last = val
+				select {
+				case ch <- Event{Value: val == high, Timestamp: time.Now()}:
+				default:
+					info("GPIO monitor on %v dropped an event; channel full\n", name)
+				}
+			}
+		}
+	}()
+
+	return ch, cleaner, nil
+}
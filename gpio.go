@@ -1,6 +1,7 @@
 package gopisysfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -21,6 +22,15 @@ const (
 	GPIOOutputLow
 	GPIOOutputHigh
 
+	// GPIOInputPullUp and GPIOInputPullDown are only honoured by the cdev backend (see
+	// NewPiCdev) - the sysfs backend has no way to express line bias and SetMode rejects them.
+	GPIOInputPullUp
+	GPIOInputPullDown
+
+	// GPIOPWM names the PWM output mode. Passing it to SetMode is an error - PWM is started and
+	// stopped with SetPWM/StopPWM, which do not change the port's mode.
+	GPIOPWM
+
 	// from https://www.kernel.org/doc/Documentation/gpio/sysfs.txt
 	direction_in     = "in"
 	direction_out    = "out"
@@ -47,13 +57,20 @@ type GPIOPort interface {
 	State() string
 	IsEnabled() bool
 	Enable() error
+	EnableContext(ctx context.Context) error
 	Reset() error
+	ResetContext(ctx context.Context) error
 	SetMode(GPIOMode) error
 	IsOutput() (bool, error)
 	SetValue(bool) error
 	SetValues(ch <-chan bool) (<-chan error, error)
+	SetValuesContext(ctx context.Context, ch <-chan bool) (<-chan error, error)
 	Value() (bool, error)
 	Values(buffersize int) (<-chan Event, error)
+	ValuesContext(ctx context.Context, buffersize int) (<-chan Event, error)
+	ValuesFiltered(buffersize int, opts FilterOptions) (<-chan Event, error)
+	SetPWM(freqHz float64, duty float64) error
+	StopPWM() error
 }
 
 type gport struct {
@@ -68,6 +85,11 @@ type gport struct {
 	export    string
 	unexport  string
 	resetters []func()
+
+	forceSoftwarePWM bool
+	softPWM          *softwarePWM
+	pwmChannel       *int
+	pwmResetterIdx   int
 }
 
 func newGPIO(host *pi, port int) *gport {
@@ -79,17 +101,18 @@ func newGPIO(host *pi, port int) *gport {
 	unexport := filepath.Join(gpio, "unexport")
 
 	return &gport{
-		mu:        sync.Mutex{},
-		host:      host,
-		port:      port,
-		sport:     sport,
-		folder:    folder,
-		value:     filepath.Join(folder, "value"),
-		direction: filepath.Join(folder, "direction"),
-		edge:      filepath.Join(folder, "edge"),
-		export:    export,
-		unexport:  unexport,
-		resetters: make([]func(), 0),
+		mu:             sync.Mutex{},
+		host:           host,
+		port:           port,
+		sport:          sport,
+		folder:         folder,
+		value:          filepath.Join(folder, "value"),
+		direction:      filepath.Join(folder, "direction"),
+		edge:           filepath.Join(folder, "edge"),
+		export:         export,
+		unexport:       unexport,
+		resetters:      make([]func(), 0),
+		pwmResetterIdx: -1,
 	}
 }
 
@@ -101,27 +124,31 @@ func (p *gport) IsEnabled() bool {
 
 	defer p.unlock(p.lock())
 
-	return checkFile(p.folder)
+	return p.host.checkFile(p.folder)
 }
 
 func (p *gport) Enable() error {
+	return p.EnableContext(context.Background())
+}
+
+func (p *gport) EnableContext(ctx context.Context) error {
 
 	defer p.unlock(p.lock())
 
-	if checkFile(p.folder) {
+	if p.host.checkFile(p.folder) {
 		return nil
 	}
 
 	info("GPIO Enabling %v\n", p)
 
-	if err := writeFile(p.export, p.sport); err != nil {
+	if err := p.host.writeFile(p.export, p.sport); err != nil {
 		return err
 	}
 
 	start := time.Now()
 
 	// wait for folder to arrive....
-	ch, err := awaitFileCreate(p.folder, timelimit)
+	ch, err := p.host.awaitFileCreate(ctx, p.folder, timelimit)
 	if err != nil {
 		return err
 	}
@@ -137,9 +164,9 @@ func (p *gport) Enable() error {
 		for {
 			remaining := timelimit - time.Since(start)
 			info("GPIO Enabling %v checking file %v state (timeout limit %v)\n", p, fname, remaining)
-			if checkFile(fname) {
+			if p.host.checkFile(fname) {
 				// exists, but check writable.... invalid data will be ignored(rejected), but permissions won't
-				if err := writeFile(fname, " "); err == nil || !os.IsPermission(err) {
+				if err := p.host.writeFile(fname, " "); err == nil || !os.IsPermission(err) {
 					info("GPIO Enabling %v file %v state OK\n", p, fname)
 					break
 				} else {
@@ -148,6 +175,8 @@ func (p *gport) Enable() error {
 			}
 			remaining = timelimit - time.Since(start)
 			select {
+			case <-ctx.Done():
+				return ctx.Err()
 			case <-time.After(remaining):
 				return fmt.Errorf("Timed out enabling GPIO %v - %v not yet writable", p.sport, fname)
 			case <-time.After(pollInterval):
@@ -163,10 +192,14 @@ func (p *gport) Enable() error {
 }
 
 func (p *gport) Reset() error {
+	return p.ResetContext(context.Background())
+}
+
+func (p *gport) ResetContext(ctx context.Context) error {
 
 	defer p.unlock(p.lock())
 
-	if !checkFile(p.folder) {
+	if !p.host.checkFile(p.folder) {
 		// already reset
 		return nil
 	}
@@ -177,10 +210,10 @@ func (p *gport) Reset() error {
 	}
 	p.resetters = nil
 
-	if err := writeFile(p.unexport, p.sport); err != nil {
+	if err := p.host.writeFile(p.unexport, p.sport); err != nil {
 		return err
 	}
-	ch, err := awaitFileRemove(p.folder, timelimit)
+	ch, err := p.host.awaitFileRemove(ctx, p.folder, timelimit)
 	if err != nil {
 		return err
 	}
@@ -217,6 +250,10 @@ func (p *gport) SetMode(mode GPIOMode) error {
 		direction = direction_outhi
 	case GPIOOutputLow:
 		direction = direction_outlow
+	case GPIOInputPullUp, GPIOInputPullDown:
+		return fmt.Errorf("GPIOMode %v is not supported by the sysfs backend; use NewPiCdev", mode)
+	case GPIOPWM:
+		return fmt.Errorf("GPIOMode %v must be started with SetPWM, not SetMode", mode)
 	default:
 		return fmt.Errorf("GPIOMode %v does not exist")
 	}
@@ -250,7 +287,7 @@ func (p *gport) State() string {
 	defer p.unlock(p.lock())
 
 	base := fmt.Sprintf("GPIO %v: ", p.sport)
-	if !checkFile(p.folder) {
+	if !p.host.checkFile(p.folder) {
 		return base + "Reset"
 	}
 
@@ -304,6 +341,10 @@ func (p *gport) SetValue(value bool) error {
 }
 
 func (p *gport) SetValues(ch <-chan bool) (<-chan error, error) {
+	return p.SetValuesContext(context.Background(), ch)
+}
+
+func (p *gport) SetValuesContext(ctx context.Context, ch <-chan bool) (<-chan error, error) {
 	defer p.unlock(p.lock())
 
 	info("GPIO Setting Values set channel on %v\n", p)
@@ -326,6 +367,9 @@ func (p *gport) SetValues(ch <-chan bool) (<-chan error, error) {
 			select {
 			case <-killer:
 				return
+			case <-ctx.Done():
+				errch <- ctx.Err()
+				return
 			case v, ok := <-ch:
 				if !ok {
 					return
@@ -344,6 +388,10 @@ func (p *gport) SetValues(ch <-chan bool) (<-chan error, error) {
 }
 
 func (p *gport) Values(buffersize int) (<-chan Event, error) {
+	return p.ValuesContext(context.Background(), buffersize)
+}
+
+func (p *gport) ValuesContext(ctx context.Context, buffersize int) (<-chan Event, error) {
 	defer p.unlock(p.lock())
 
 	info("GPIO Setting Value channel on %v\n", p)
@@ -358,7 +406,7 @@ func (p *gport) Values(buffersize int) (<-chan Event, error) {
 		return nil, err
 	}
 
-	ch, cleaner, err := buildMonitor(p.value, buffersize)
+	ch, cleaner, err := p.host.buildMonitor(ctx, p.value, buffersize)
 	if err != nil {
 		return nil, err
 	}
@@ -367,32 +415,130 @@ func (p *gport) Values(buffersize int) (<-chan Event, error) {
 	return ch, nil
 }
 
+// ForceSoftwarePWM overrides the automatic hardware/software PWM selection made by SetPWM,
+// letting callers keep a hardware-capable line (GPIO12/13/18/19) on the software path, for
+// example to free up the Pi's PWM channel for another line.
+func (p *gport) ForceSoftwarePWM(force bool) {
+	defer p.unlock(p.lock())
+	p.forceSoftwarePWM = force
+}
+
+// SetPWM starts driving a PWM waveform of the given frequency (Hz) and duty cycle (0-1) on
+// this port. GPIO12, GPIO13, GPIO18 and GPIO19 are wired to the Pi's two hardware PWM
+// channels and use /sys/class/pwm/pwmchip0 for jitter-free output; every other line falls
+// back to toggling the line from a goroutine, which is far less accurate - expect tens of
+// microseconds of jitter bounded by the Go scheduler, not suitable for precise timing.
+// ForceSoftwarePWM can override the automatic hardware/software choice.
+func (p *gport) SetPWM(freqHz float64, duty float64) error {
+	defer p.unlock(p.lock())
+
+	if err := p.checkEnabled(); err != nil {
+		return err
+	}
+
+	if err := p.stopPWM(); err != nil {
+		return err
+	}
+
+	if channel, ok := hardwarePWMLines[p.port]; ok && !p.forceSoftwarePWM {
+		info("GPIO Starting hardware PWM on %v (channel %v)\n", p, channel)
+		if err := p.host.startHardwarePWM(channel, freqHz, duty); err != nil {
+			return err
+		}
+		p.pwmChannel = &channel
+		p.resetters = append(p.resetters, func() { p.host.stopHardwarePWM(channel) })
+		p.pwmResetterIdx = len(p.resetters) - 1
+		return nil
+	}
+
+	info("GPIO Starting software PWM on %v\n", p)
+	sw, err := startSoftwarePWM(p.writeValue, freqHz, duty)
+	if err != nil {
+		return err
+	}
+	p.softPWM = sw
+	p.resetters = append(p.resetters, sw.Stop)
+	p.pwmResetterIdx = len(p.resetters) - 1
+	return nil
+}
+
+// StopPWM stops any PWM waveform started by SetPWM. It is a no-op if SetPWM was never called.
+func (p *gport) StopPWM() error {
+	defer p.unlock(p.lock())
+	return p.stopPWM()
+}
+
+// stopPWM stops any running PWM waveform and clears its resetter; SetPWM also calls it before
+// starting a new waveform, so a second SetPWM without an intervening StopPWM replaces rather
+// than leaks the previous one. Callers must hold p.mu.
+func (p *gport) stopPWM() error {
+	p.clearPWMResetter()
+
+	if p.softPWM != nil {
+		p.softPWM.Stop()
+		p.softPWM = nil
+	}
+	if p.pwmChannel != nil {
+		err := p.host.stopHardwarePWM(*p.pwmChannel)
+		p.pwmChannel = nil
+		return err
+	}
+	return nil
+}
+
+// clearPWMResetter drops the resetter SetPWM registered for the current PWM waveform, so that
+// a later Reset doesn't call sw.Stop/stopHardwarePWM a second time.
+func (p *gport) clearPWMResetter() {
+	if p.pwmResetterIdx < 0 {
+		return
+	}
+	p.resetters = append(p.resetters[:p.pwmResetterIdx], p.resetters[p.pwmResetterIdx+1:]...)
+	p.pwmResetterIdx = -1
+}
+
+// ValuesFiltered is like Values, but debounces and rate-limits the raw sysfs edge interrupts
+// per opts before emitting them - a mechanical switch otherwise fires dozens of spurious
+// edges per press, which would otherwise have to be filtered by every caller of Values.
+func (p *gport) ValuesFiltered(buffersize int, opts FilterOptions) (<-chan Event, error) {
+	raw, err := p.Values(buffersize)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan bool)
+	p.mu.Lock()
+	p.resetters = append(p.resetters, func() { close(done) })
+	p.mu.Unlock()
+
+	return filterEvents(raw, buffersize, opts, done), nil
+}
+
 func (p *gport) writeEdge(edges string) error {
-	return writeFile(p.edge, edges)
+	return p.host.writeFile(p.edge, edges)
 }
 
 func (p *gport) readEdge() (string, error) {
-	return readFile(p.edge)
+	return p.host.readFile(p.edge)
 }
 
 func (p *gport) writeDirection(direction string) error {
-	return writeFile(p.direction, direction)
+	return p.host.writeFile(p.direction, direction)
 }
 
 func (p *gport) readDirection() (string, error) {
-	return readFile(p.direction)
+	return p.host.readFile(p.direction)
 }
 
 func (p *gport) writeValue(value string) error {
-	return writeFile(p.value, value)
+	return p.host.writeFile(p.value, value)
 }
 
 func (p *gport) readValue() (string, error) {
-	return readFile(p.value)
+	return p.host.readFile(p.value)
 }
 
 func (p *gport) checkEnabled() error {
-	if checkFile(p.folder) {
+	if p.host.checkFile(p.folder) {
 		return nil
 	}
 	return fmt.Errorf("GPIO %v is not enabled", p.port)
@@ -0,0 +1,23 @@
+package gopisysfs
+
+import (
+	"time"
+)
+
+// pollInterval is how frequently the await* helpers and the value-change monitor re-check
+// sysfs state while waiting for the kernel (or a simulated host) to catch up.
+const pollInterval = 10 * time.Millisecond
+
+var logFn func(format string, args ...interface{})
+
+// SetLogFn installs a logging function (for example t.Logf in tests) that receives the
+// library's internal diagnostic messages. Passing nil (the default) disables logging.
+func SetLogFn(fn func(format string, args ...interface{})) {
+	logFn = fn
+}
+
+func info(format string, args ...interface{}) {
+	if logFn != nil {
+		logFn(format, args...)
+	}
+}
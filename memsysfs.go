@@ -0,0 +1,136 @@
+package gopisysfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// memorySysfs simulates the subset of the Linux GPIO sysfs tree that gport relies on:
+// writing a line number to "<gpiodir>/export" creates "<gpiodir>/gpio<N>" with direction,
+// value and edge files, and writing to "unexport" removes it again. It is backed entirely by
+// an in-memory afero.MemMapFs, so a whole GPIOPort state machine can be driven in tests
+// without real hardware.
+type memorySysfs struct {
+	afero.Fs
+	gpiodir string
+}
+
+// NewMemorySysfs returns an afero.Fs that behaves like the sysfs GPIO tree rooted at gpiodir,
+// backed entirely in memory. Pass it to NewPiWithFS to exercise gport logic in unit tests.
+func NewMemorySysfs(gpiodir string) afero.Fs {
+	mem := &memorySysfs{Fs: afero.NewMemMapFs(), gpiodir: gpiodir}
+	mem.Fs.MkdirAll(gpiodir, 0755)
+	mem.Fs.Create(filepath.Join(gpiodir, "export"))
+	mem.Fs.Create(filepath.Join(gpiodir, "unexport"))
+	return mem
+}
+
+func (m *memorySysfs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	switch name {
+	case filepath.Join(m.gpiodir, "export"):
+		return m.control(name, flag, perm, m.export)
+	case filepath.Join(m.gpiodir, "unexport"):
+		return m.control(name, flag, perm, m.unexport)
+	}
+	if folder := filepath.Dir(name); filepath.Base(name) == "direction" && filepath.Dir(folder) == m.gpiodir {
+		return m.directionControl(name, flag, perm, folder)
+	}
+	return m.Fs.OpenFile(name, flag, perm)
+}
+
+// control wraps the underlying export/unexport file so that writes to it trigger action
+// with the written line number, mimicking the kernel driver's behaviour.
+func (m *memorySysfs) control(name string, flag int, perm os.FileMode, action func(string) error) (afero.File, error) {
+	f, err := m.Fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &controlFile{File: f, action: action}, nil
+}
+
+func (m *memorySysfs) export(port string) error {
+	folder := filepath.Join(m.gpiodir, fmt.Sprintf("gpio%v", port))
+	if err := m.Fs.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+	for name, value := range map[string]string{"direction": direction_in, "value": low, "edge": "none"} {
+		if err := afero.WriteFile(m.Fs, filepath.Join(folder, name), []byte(value), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memorySysfs) unexport(port string) error {
+	folder := filepath.Join(m.gpiodir, fmt.Sprintf("gpio%v", port))
+	return m.Fs.RemoveAll(folder)
+}
+
+// directionControl wraps a line's direction file so that, like the real kernel driver, writing
+// "high" or "low" to it also sets the line's initial output value - unlike export/unexport,
+// the direction value itself still has to be persisted for readDirection to see it.
+func (m *memorySysfs) directionControl(name string, flag int, perm os.FileMode, folder string) (afero.File, error) {
+	f, err := m.Fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &directionFile{File: f, fs: m.Fs, folder: folder}, nil
+}
+
+// setInitialValue mimics the kernel setting a line's value as a side effect of writing "high"
+// or "low" to its direction file.
+func setInitialValue(fs afero.Fs, folder, direction string) error {
+	switch direction {
+	case direction_outhi:
+		return afero.WriteFile(fs, filepath.Join(folder, "value"), []byte(high), 0644)
+	case direction_outlow:
+		return afero.WriteFile(fs, filepath.Join(folder, "value"), []byte(low), 0644)
+	}
+	return nil
+}
+
+// controlFile intercepts writes made to export/unexport so the simulator can react to the
+// GPIO line number being written, then delegates everything else to the underlying file.
+type controlFile struct {
+	afero.File
+	action func(string) error
+}
+
+func (c *controlFile) Write(p []byte) (int, error) {
+	if err := c.action(strings.TrimSpace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *controlFile) WriteString(s string) (int, error) {
+	return c.Write([]byte(s))
+}
+
+// directionFile intercepts writes made to a line's direction file so the simulator can mimic
+// the kernel setting the line's initial output value as a side effect of writing "high" or
+// "low" to direction, while still persisting the write itself for readDirection to see.
+type directionFile struct {
+	afero.File
+	fs     afero.Fs
+	folder string
+}
+
+func (d *directionFile) Write(p []byte) (int, error) {
+	n, err := d.File.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if err := setInitialValue(d.fs, d.folder, strings.TrimSpace(string(p))); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (d *directionFile) WriteString(s string) (int, error) {
+	return d.Write([]byte(s))
+}
@@ -0,0 +1,39 @@
+package gopisysfs
+
+import (
+	"testing"
+)
+
+func TestMemorySysfsExportUnexport(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	port := host.GPIO(4)
+
+	if port.IsEnabled() {
+		t.Fatal("expected port 4 to start disabled")
+	}
+
+	if err := port.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if !port.IsEnabled() {
+		t.Fatal("expected port 4 to be enabled after Enable()")
+	}
+
+	if err := port.SetMode(GPIOOutputHigh); err != nil {
+		t.Fatal(err)
+	}
+	val, err := port.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !val {
+		t.Fatal("expected port 4 to read high after SetMode(GPIOOutputHigh)")
+	}
+
+	if err := port.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if port.IsEnabled() {
+		t.Fatal("expected port 4 to be disabled after Reset()")
+	}
+}
@@ -0,0 +1,55 @@
+package gopisysfs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sys_model   = "proc/device-tree/model"
+	sys_cpuinfo = "proc/cpuinfo"
+)
+
+// root is the filesystem prefix used when locating host identification files. Tests point
+// it at a fixture tree via setRoot so they don't depend on running on a real Raspberry Pi.
+var root = "/"
+
+// setRoot overrides the filesystem root used when locating system information files.
+func setRoot(abs string) {
+	root = abs
+}
+
+// file resolves path elements relative to the configured root.
+func file(parts ...string) string {
+	return filepath.Join(append([]string{root}, parts...)...)
+}
+
+// readFilePanic reads name and panics if it cannot be read, for files that are expected to
+// always be present and readable on the target platform.
+func readFilePanic(name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readRevision extracts the "Revision" field from /proc/cpuinfo, which Raspberry Pi boards
+// use to identify the exact board variant.
+func readRevision() string {
+	data, err := ioutil.ReadFile(file(sys_cpuinfo))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "Revision") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
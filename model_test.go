@@ -0,0 +1,40 @@
+package gopisysfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModel(t *testing.T) {
+	dir := t.TempDir()
+	setRoot(dir)
+	defer setRoot("/")
+
+	modelFile := file(sys_model)
+	if err := os.MkdirAll(filepath.Dir(modelFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(modelFile, []byte("Raspberry Pi 4 Model B Rev 1.2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cpuinfoFile := file(sys_cpuinfo)
+	if err := os.MkdirAll(filepath.Dir(cpuinfoFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cpuinfoFile, []byte("Hardware\t: BCM2835\nRevision\t: c03111\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model := readFilePanic(modelFile)
+	if model == "" {
+		t.Errorf("Unable to get model")
+	}
+	revision := readRevision()
+	if revision != "c03111" {
+		t.Errorf("Expected revision c03111, got %v", revision)
+	}
+
+	t.Logf("Got model %v and revision %v", model, revision)
+}
@@ -0,0 +1,104 @@
+package gopisysfs
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+)
+
+// defaultGPIODir is the sysfs GPIO tree on a real Raspberry Pi.
+const defaultGPIODir = "/sys/class/gpio"
+
+// defaultChipPath is the character device for the Pi's main GPIO controller.
+const defaultChipPath = "/dev/gpiochip0"
+
+// backend selects which kernel interface a pi host talks to. Sysfs GPIO is deprecated in
+// mainline kernels (and removed entirely on some Pi 5 images), but is kept as the default so
+// existing callers of NewPi are unaffected.
+type backend int
+
+const (
+	backendSysfs backend = iota
+	backendCdev
+)
+
+// pi represents the GPIO-capable host that port instances are created against. A sysfs-backed
+// host routes every file access through fs, so swapping fs is enough to run the whole GPIOPort
+// state machine against an in-memory simulator instead of the real kernel. A cdev-backed host
+// instead talks to a GPIO character device directly via ioctls.
+type pi struct {
+	backend  backend
+	gpiodir  string
+	fs       afero.Fs
+	chipPath string
+	chipFd   int
+}
+
+// NewPi creates a host that manages GPIO lines through the real Raspberry Pi sysfs tree. It
+// is equivalent to NewPiSysfs and is kept for backward compatibility.
+func NewPi() *pi {
+	return NewPiSysfs()
+}
+
+// NewPiSysfs creates a host that manages GPIO lines through the deprecated sysfs tree
+// (/sys/class/gpio). Prefer NewPiCdev on kernels that still provide /dev/gpiochipN.
+func NewPiSysfs() *pi {
+	return NewPiWithFS(afero.NewOsFs())
+}
+
+// NewPiWithFS creates a sysfs-backed host using the supplied afero.Fs. Pass NewMemorySysfs()
+// to exercise GPIOPort logic without real hardware, or any other afero.Fs (a recording or
+// tracing fs, for example) for debugging.
+func NewPiWithFS(fs afero.Fs) *pi {
+	return &pi{
+		backend: backendSysfs,
+		gpiodir: defaultGPIODir,
+		fs:      fs,
+	}
+}
+
+// newCdevPortFn and openChipFn are wired up by cdev.go (build-tag linux) so NewPiCdev and GPIO
+// can reach the character-device backend. They stay nil on platforms that don't compile that
+// file, in which case NewPiCdev reports that the backend isn't available there.
+var (
+	newCdevPortFn func(host *pi, port int) GPIOPort
+	openChipFn    func(chipPath string) (int, error)
+)
+
+// NewPiCdev creates a host that manages GPIO lines through the Linux GPIO character device
+// ABI (/dev/gpiochipN), the replacement for the deprecated sysfs interface. chipPath is
+// typically "/dev/gpiochip0" for the Pi's main controller. This backend is only compiled in on
+// Linux; on other platforms it returns an error.
+func NewPiCdev(chipPath string) (*pi, error) {
+	if openChipFn == nil {
+		return nil, fmt.Errorf("cdev GPIO backend is not available on this platform")
+	}
+	fd, err := openChipFn(chipPath)
+	if err != nil {
+		return nil, err
+	}
+	return &pi{
+		backend:  backendCdev,
+		chipPath: chipPath,
+		chipFd:   fd,
+		// PWM and other auxiliary subsystems are only ever exposed through sysfs, regardless
+		// of which interface is used for plain digital GPIO, so a cdev host still needs an fs.
+		fs: afero.NewOsFs(),
+	}, nil
+}
+
+func (p *pi) String() string {
+	if p.backend == backendCdev {
+		return fmt.Sprintf("pi(%v)", p.chipPath)
+	}
+	return fmt.Sprintf("pi(%v)", p.gpiodir)
+}
+
+// GPIO returns the GPIOPort for the given GPIO line number/offset, backed by whichever
+// interface this host was constructed with.
+func (p *pi) GPIO(port int) GPIOPort {
+	if p.backend == backendCdev && newCdevPortFn != nil {
+		return newCdevPortFn(p, port)
+	}
+	return newGPIO(p, port)
+}
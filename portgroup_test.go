@@ -0,0 +1,42 @@
+package gopisysfs
+
+import (
+	"testing"
+)
+
+func TestPortGroupSequentialSetReadBits(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	group := host.PortGroup(4, 17, 27)
+
+	for _, port := range group.ports {
+		if err := port.Enable(); err != nil {
+			t.Fatal(err)
+		}
+		if err := port.SetMode(GPIOOutput); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := group.SetBits(0b111, 0b101); err != nil {
+		t.Fatal(err)
+	}
+
+	bits, err := group.ReadBits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits != 0b101 {
+		t.Errorf("expected bits 0b101, got %b", bits)
+	}
+
+	if err := group.SetBits(0b010, 0b010); err != nil {
+		t.Fatal(err)
+	}
+	bits, err = group.ReadBits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits != 0b111 {
+		t.Errorf("expected bits 0b111 after partial set, got %b", bits)
+	}
+}
@@ -0,0 +1,74 @@
+package gopisysfs
+
+import (
+	"fmt"
+	"time"
+)
+
+// hardwarePWMLines maps the BCM GPIO numbers wired to the Pi's two hardware PWM channels to
+// the channel (pwmchip0's "pwmN") they drive.
+var hardwarePWMLines = map[int]int{
+	12: 0, 18: 0, // PWM channel 0
+	13: 1, 19: 1, // PWM channel 1
+}
+
+// softwarePWM drives a PWM waveform on a GPIO line by toggling its value from a goroutine on
+// a timer, for lines with no hardware PWM channel.
+type softwarePWM struct {
+	stop chan bool
+}
+
+func startSoftwarePWM(writeValue func(string) error, freqHz, duty float64) (*softwarePWM, error) {
+	if freqHz <= 0 {
+		return nil, fmt.Errorf("PWM frequency must be positive, got %v", freqHz)
+	}
+	if duty < 0 || duty > 1 {
+		return nil, fmt.Errorf("PWM duty cycle must be between 0 and 1, got %v", duty)
+	}
+
+	period := time.Duration(float64(time.Second) / freqHz)
+	on := time.Duration(float64(period) * duty)
+	off := period - on
+
+	sw := &softwarePWM{stop: make(chan bool)}
+	go func() {
+		for {
+			if on > 0 {
+				writeValue(high)
+			}
+			if sw.sleep(on) {
+				return
+			}
+			if off > 0 {
+				writeValue(low)
+			}
+			if sw.sleep(off) {
+				return
+			}
+		}
+	}()
+	return sw, nil
+}
+
+// sleep waits for d, or for Stop to be called, whichever comes first, reporting whether it
+// was stopped.
+func (sw *softwarePWM) sleep(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-sw.stop:
+			return true
+		default:
+			return false
+		}
+	}
+	select {
+	case <-sw.stop:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func (sw *softwarePWM) Stop() {
+	close(sw.stop)
+}
@@ -0,0 +1,64 @@
+package gopisysfs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// pwmchipDir is the sysfs tree for the Pi's hardware PWM controller.
+const pwmchipDir = "/sys/class/pwm/pwmchip0"
+
+// startHardwarePWM exports (if needed) and configures PWM channel on the host's pwmchip0,
+// driving freqHz/duty through its period and duty_cycle files (both in nanoseconds).
+func (p *pi) startHardwarePWM(channel int, freqHz, duty float64) error {
+	if freqHz <= 0 {
+		return fmt.Errorf("PWM frequency must be positive, got %v", freqHz)
+	}
+	if duty < 0 || duty > 1 {
+		return fmt.Errorf("PWM duty cycle must be between 0 and 1, got %v", duty)
+	}
+
+	folder := p.pwmChannelDir(channel)
+	if !p.checkFile(folder) {
+		if err := p.writeFile(filepath.Join(pwmchipDir, "export"), fmt.Sprintf("%d", channel)); err != nil {
+			return err
+		}
+		ch, err := p.awaitFileCreate(context.Background(), folder, timelimit)
+		if err != nil {
+			return err
+		}
+		if err := <-ch; err != nil {
+			return err
+		}
+	}
+
+	periodNs := uint64(1e9 / freqHz)
+	dutyNs := uint64(float64(periodNs) * duty)
+
+	// the kernel rejects a duty_cycle greater than the current period, so always clear it
+	// before writing a new period.
+	if err := p.writeFile(filepath.Join(folder, "duty_cycle"), "0"); err != nil {
+		return err
+	}
+	if err := p.writeFile(filepath.Join(folder, "period"), fmt.Sprintf("%d", periodNs)); err != nil {
+		return err
+	}
+	if err := p.writeFile(filepath.Join(folder, "duty_cycle"), fmt.Sprintf("%d", dutyNs)); err != nil {
+		return err
+	}
+	return p.writeFile(filepath.Join(folder, "enable"), "1")
+}
+
+// stopHardwarePWM disables and unexports channel.
+func (p *pi) stopHardwarePWM(channel int) error {
+	folder := p.pwmChannelDir(channel)
+	if err := p.writeFile(filepath.Join(folder, "enable"), "0"); err != nil {
+		return err
+	}
+	return p.writeFile(filepath.Join(pwmchipDir, "unexport"), fmt.Sprintf("%d", channel))
+}
+
+func (p *pi) pwmChannelDir(channel int) string {
+	return filepath.Join(pwmchipDir, fmt.Sprintf("pwm%d", channel))
+}
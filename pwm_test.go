@@ -0,0 +1,73 @@
+package gopisysfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoftwarePWMStartStop(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	port := host.GPIO(4) // not a hardware-PWM-capable line, so this exercises the software path
+
+	if err := port.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.SetMode(GPIOOutput); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port.SetPWM(1000, 0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if err := port.StopPWM(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port.Reset(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSetPWMReplacesExisting checks that calling SetPWM a second time without an intervening
+// StopPWM stops the previous waveform's goroutine instead of leaking it alongside the new one.
+func TestSetPWMReplacesExisting(t *testing.T) {
+	host := NewPiWithFS(NewMemorySysfs("/sys/class/gpio"))
+	port := host.GPIO(4).(*gport)
+
+	if err := port.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.SetMode(GPIOOutput); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port.SetPWM(1000, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	first := port.softPWM
+
+	if err := port.SetPWM(500, 0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-first.stop:
+		// the first software PWM goroutine was stopped, as expected
+	default:
+		t.Fatal("expected the first SetPWM's software PWM to be stopped by the second SetPWM")
+	}
+
+	if len(port.resetters) != 1 {
+		t.Fatalf("expected exactly one PWM resetter registered, got %v", len(port.resetters))
+	}
+
+	if err := port.StopPWM(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.Reset(); err != nil {
+		t.Fatal(err)
+	}
+}
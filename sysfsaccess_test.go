@@ -1,77 +1,66 @@
 package gopisysfs
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"testing"
 	"time"
-)
 
-var nowtime string
+	"github.com/spf13/afero"
+)
 
-func init() {
-	abs, _ := filepath.Abs("testdata")
-	setRoot(abs)
-	nowtime = fmt.Sprintf("%v", time.Now().UnixNano())
+func testPi(t *testing.T) *pi {
+	dir := t.TempDir()
+	return NewPiWithFS(afero.NewBasePathFs(afero.NewOsFs(), dir))
 }
 
-func tmpFile(ext string) string {
-	return file("tmp", fmt.Sprintf("gopitest.%v.%v.%v", os.Getpid(), nowtime, ext))
+func tmpFile(p *pi, ext string) string {
+	name := fmt.Sprintf("gopitest.%v.%v.%v", os.Getpid(), time.Now().UnixNano(), ext)
+	p.fs.Create(name)
+	p.fs.Remove(name)
+	return name
 }
 
 func TestCheck(t *testing.T) {
-	name := tmpFile("checkfile")
-	if checkFile(name) {
+	p := testPi(t)
+	name := tmpFile(p, "checkfile")
+	if p.checkFile(name) {
 		t.Errorf("Expected file %v to not exist, but it does", name)
 	}
-	writeFile(name, "boo")
-	if !checkFile(name) {
+	p.writeFile(name, "boo")
+	if !p.checkFile(name) {
 		t.Errorf("Expected file %v to exist, but it does not", name)
 	}
 }
 
-func TestModel(t *testing.T) {
-	t.Log("Testing details")
-	model := readFilePanic(file(sys_model))
-	if model == "" {
-		t.Errorf("Unable to get model")
-	}
-	revision := readRevision()
-	if revision == "" {
-		t.Errorf("Unable to get revision")
-	}
-
-	t.Logf("Got Got model %v and revision %v", model, revision)
-
-}
-
 func TestWriteReadFile(t *testing.T) {
-	name := tmpFile("readwrite")
-	err := writeFile(name, "boo")
+	p := testPi(t)
+	name := tmpFile(p, "readwrite")
+	err := p.writeFile(name, "boo")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	val, err := readFile(name)
+	val, err := p.readFile(name)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if val != "boo" {
 		t.Errorf("Expected to read '%v' but got '%v'", "boo", val)
 	}
-
 }
 
 func TestAwaitFileExists(t *testing.T) {
 	SetLogFn(t.Logf)
-	name := tmpFile("awaitpre")
+	p := testPi(t)
+	name := tmpFile(p, "awaitpre")
 	t.Logf("Using test file %v", name)
-	err := writeFile(name, "boo")
+	err := p.writeFile(name, "boo")
 	if err != nil {
 		t.Fatal(err)
 	}
-	ch, err := awaitFileCreate(name, 2*time.Second)
+	ch, err := p.awaitFileCreate(context.Background(), name, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,25 +75,25 @@ func TestAwaitFileExists(t *testing.T) {
 	}
 
 	t.Logf("Checking file contents\n")
-	data, err := readFile(name)
+	data, err := p.readFile(name)
 	if data != "boo" {
 		t.Fatalf("Expected to read boo but got: %v", data)
 	}
-
 }
 
 func TestAwaitFile(t *testing.T) {
 	SetLogFn(t.Logf)
-	name := tmpFile("awaitpost")
+	p := testPi(t)
+	name := tmpFile(p, "awaitpost")
 	t.Logf("Using test file %v", name)
-	ch, err := awaitFileCreate(name, 2*time.Second)
+	ch, err := p.awaitFileCreate(context.Background(), name, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 	go func() {
 		<-time.After(200 * time.Millisecond)
 		t.Logf("About to write %v\n", name)
-		writeFile(name, "boo")
+		p.writeFile(name, "boo")
 		t.Logf("Wrote %v\n", name)
 	}()
 	t.Logf("About to wait on channel\n")
@@ -118,18 +107,18 @@ func TestAwaitFile(t *testing.T) {
 	}
 
 	t.Logf("Checking file contents\n")
-	data, err := readFile(name)
+	data, err := p.readFile(name)
 	if data != "boo" {
 		t.Fatalf("Expected to read boo but got: %v", data)
 	}
-
 }
 
 func TestAwaitRemoveGone(t *testing.T) {
 	SetLogFn(t.Logf)
-	name := tmpFile("awaitremovepre")
+	p := testPi(t)
+	name := tmpFile(p, "awaitremovepre")
 	t.Logf("Using test file %v", name)
-	ch, err := awaitFileRemove(name, 2*time.Second)
+	ch, err := p.awaitFileRemove(context.Background(), name, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -149,27 +138,27 @@ func TestAwaitRemoveGone(t *testing.T) {
 
 func TestAwaitRemoveStill(t *testing.T) {
 	SetLogFn(t.Logf)
-	name := tmpFile("awaitremovepost")
-	t.Logf("Using test file %v", name)
-	err := writeFile(name, name)
+	p := testPi(t)
+	name := tmpFile(p, "awaitremovepost")
+	err := p.writeFile(name, name)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ch, err := awaitFileRemove(name, 2*time.Second)
+	ch, err := p.awaitFileRemove(context.Background(), name, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	go func() {
 		<-time.After(100 * time.Millisecond)
-		os.Remove(name)
+		p.fs.Remove(name)
 	}()
 
 	// a little delay here.
 	<-time.After(50 * time.Millisecond)
 
-	if !checkFile(name) {
+	if !p.checkFile(name) {
 		t.Fatalf("Test file %v removed too early for some reason", name)
 	}
 
@@ -184,5 +173,4 @@ func TestAwaitRemoveStill(t *testing.T) {
 			t.Fatal("Error channel prematurely closed")
 		}
 	}
-
 }